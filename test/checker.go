@@ -0,0 +1,9 @@
+package test
+
+// Checker is the common interface every post-condition assertion in this
+// package implements. It lives outside a `_test.go` file (unlike the rest of
+// this package) so that non-test packages - such as test/golden, which needs
+// to return a Checker from GoldenDir - can import it.
+type Checker interface {
+	Check(stdout, stderr string, err error) error
+}
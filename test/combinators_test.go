@@ -0,0 +1,118 @@
+package test
+
+import (
+	"fmt"
+	"strings"
+)
+
+// And returns a Checker that succeeds only if every one of checkers
+// succeeds. Checkers run in order and And stops at the first failure,
+// returning that checker's error.
+func And(checkers ...Checker) Checker {
+	return andChecker{checkers: checkers}
+}
+
+type andChecker struct {
+	checkers []Checker
+}
+
+func (a andChecker) Check(stdout, stderr string, err error) error {
+	for _, c := range a.checkers {
+		if cErr := c.Check(stdout, stderr, err); cErr != nil {
+			return fmt.Errorf("%s: %s", InferName(c), cErr)
+		}
+	}
+	return nil
+}
+
+func (a andChecker) Name() string {
+	return fmt.Sprintf("(%s)", joinNames(a.checkers, " ∧ "))
+}
+
+// Or returns a Checker that succeeds if at least one of checkers succeeds.
+// Checkers run in order and Or stops at the first success. If every checker
+// fails, the returned error aggregates each of their individual messages.
+func Or(checkers ...Checker) Checker {
+	return orChecker{checkers: checkers}
+}
+
+type orChecker struct {
+	checkers []Checker
+}
+
+func (o orChecker) Check(stdout, stderr string, err error) error {
+	var failures []string
+	for _, c := range o.checkers {
+		if cErr := c.Check(stdout, stderr, err); cErr == nil {
+			return nil
+		} else {
+			failures = append(failures, fmt.Sprintf("%s: %s", InferName(c), cErr))
+		}
+	}
+	return fmt.Errorf("none of %d checker(s) succeeded:\n%s", len(o.checkers), strings.Join(failures, "\n"))
+}
+
+func (o orChecker) Name() string {
+	return fmt.Sprintf("(%s)", joinNames(o.checkers, " ∨ "))
+}
+
+// Not returns a Checker that inverts inner: it succeeds iff inner fails.
+func Not(inner Checker) Checker {
+	return notChecker{inner: inner}
+}
+
+type notChecker struct {
+	inner Checker
+}
+
+func (n notChecker) Check(stdout, stderr string, err error) error {
+	if cErr := n.inner.Check(stdout, stderr, err); cErr == nil {
+		return fmt.Errorf("%s unexpectedly succeeded", InferName(n.inner))
+	}
+	return nil
+}
+
+func (n notChecker) Name() string {
+	return fmt.Sprintf("¬%s", InferName(n.inner))
+}
+
+// AllOf returns a Checker that runs every one of checkers unconditionally -
+// unlike And, it never short-circuits - and aggregates every failure into a
+// single error. Use this over And when a failing report should surface all
+// of what's wrong at once rather than just the first broken condition.
+func AllOf(checkers ...Checker) Checker {
+	return allOfChecker{checkers: checkers}
+}
+
+type allOfChecker struct {
+	checkers []Checker
+}
+
+func (a allOfChecker) Check(stdout, stderr string, err error) error {
+	var failures []string
+	for _, c := range a.checkers {
+		if cErr := c.Check(stdout, stderr, err); cErr != nil {
+			failures = append(failures, fmt.Sprintf("%s: %s", InferName(c), cErr))
+		}
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("%d of %d checker(s) failed:\n%s", len(failures), len(a.checkers), strings.Join(failures, "\n"))
+	}
+	return nil
+}
+
+func (a allOfChecker) Name() string {
+	return fmt.Sprintf("AllOf(%s)", strings.Join(namesOf(a.checkers), ", "))
+}
+
+func namesOf(checkers []Checker) []string {
+	names := make([]string, len(checkers))
+	for i, c := range checkers {
+		names[i] = InferName(c)
+	}
+	return names
+}
+
+func joinNames(checkers []Checker, sep string) string {
+	return strings.Join(namesOf(checkers), sep)
+}
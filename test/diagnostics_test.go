@@ -0,0 +1,218 @@
+package test
+
+import (
+	"bufio"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Severity classifies a parsed Diagnostic.
+type Severity int
+
+const (
+	// SeverityError marks a diagnostic produced by a failed build/validate step.
+	SeverityError Severity = iota
+	// SeverityWarning marks a non-fatal diagnostic.
+	SeverityWarning
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	}
+
+	panic(fmt.Sprintf("Unknown severity value: %d", s))
+}
+
+// Diagnostic is a single structured failure or warning, either parsed out of
+// Packer's stdout/stderr (in which case Message is populated) or declared as
+// an expectation passed to Diagnostics (in which case MessageRegexp is
+// populated instead).
+type Diagnostic struct {
+	// Severity of the diagnostic.
+	Severity Severity
+	// Builder is the build-step name reported via an `==> builder: ` prefix,
+	// if any.
+	Builder string
+	// File is the template file the diagnostic points at, if any.
+	File string
+	// Line is the 1-indexed line within File, if any.
+	Line int
+	// Message is the diagnostic's free-form text, as parsed from output.
+	Message string
+	// MessageRegexp is a regexp that Message must match. Only meaningful on
+	// expected Diagnostic entries passed to Diagnostics.
+	MessageRegexp string
+}
+
+var (
+	diagHCLRangeRe = regexp.MustCompile(`^(?P<file>[\w./-]+\.(?:pkr\.hcl|hcl|json)):(?P<line>\d+),\d+(?:-\d+)?:\s*(?P<message>.*)$`)
+	diagErrorRe    = regexp.MustCompile(`^Error:\s*(?P<message>.*)$`)
+	diagBulletRe   = regexp.MustCompile(`^\*\s+(?P<message>.*)$`)
+	diagBuilderRe  = regexp.MustCompile(`^==>\s*(?P<builder>[\w.-]+):\s*(?P<message>.*)$`)
+	diagWarnRe     = regexp.MustCompile(`(?i)^warn(ing)?:?\s*(?P<message>.*)$`)
+)
+
+// parseDiagnostics walks stdout and stderr line by line and extracts any
+// Diagnostic it recognizes. The returned slice is sorted by File, then Line,
+// then Message so that comparisons against expected diagnostics are
+// order-independent of however Packer happened to interleave the streams.
+func parseDiagnostics(stdout, stderr string) []Diagnostic {
+	var diags []Diagnostic
+
+	for _, stream := range []string{stdout, stderr} {
+		var currentBuilder string
+		scanner := bufio.NewScanner(strings.NewReader(stream))
+		for scanner.Scan() {
+			line := strings.TrimRight(scanner.Text(), "\r")
+
+			if m := diagBuilderRe.FindStringSubmatch(line); m != nil {
+				currentBuilder = m[1]
+				line = m[2]
+			}
+
+			switch {
+			case diagHCLRangeRe.MatchString(line):
+				m := diagHCLRangeRe.FindStringSubmatch(line)
+				lineNum, _ := strconv.Atoi(m[2])
+				severity := SeverityError
+				message := m[3]
+				if wm := diagWarnRe.FindStringSubmatch(message); wm != nil {
+					severity = SeverityWarning
+					message = wm[2]
+				}
+				diags = append(diags, Diagnostic{
+					Severity: severity,
+					Builder:  currentBuilder,
+					File:     m[1],
+					Line:     lineNum,
+					Message:  message,
+				})
+			case diagErrorRe.MatchString(line):
+				m := diagErrorRe.FindStringSubmatch(line)
+				diags = append(diags, Diagnostic{
+					Severity: SeverityError,
+					Builder:  currentBuilder,
+					Message:  m[1],
+				})
+			case diagBulletRe.MatchString(line):
+				m := diagBulletRe.FindStringSubmatch(line)
+				diags = append(diags, Diagnostic{
+					Severity: SeverityError,
+					Builder:  currentBuilder,
+					Message:  m[1],
+				})
+			case diagWarnRe.MatchString(line):
+				m := diagWarnRe.FindStringSubmatch(line)
+				diags = append(diags, Diagnostic{
+					Severity: SeverityWarning,
+					Builder:  currentBuilder,
+					Message:  m[2],
+				})
+			}
+		}
+	}
+
+	sort.Slice(diags, func(i, j int) bool {
+		if diags[i].File != diags[j].File {
+			return diags[i].File < diags[j].File
+		}
+		if diags[i].Line != diags[j].Line {
+			return diags[i].Line < diags[j].Line
+		}
+		return diags[i].Message < diags[j].Message
+	})
+
+	return diags
+}
+
+// Diagnostics is a Checker that parses the full set of error/warning
+// diagnostics out of a command's output and verifies that every entry in
+// `expect` is present, matching on Severity, Builder/File/Line (when set)
+// and MessageRegexp.
+//
+// Unlike Grep, which only asserts that a single pattern matches somewhere in
+// the output, Diagnostics surfaces every failure Packer reported at once,
+// with file/line context, so a test can assert on the complete diagnostic
+// set rather than a single regexp hit.
+type Diagnostics struct {
+	expect []Diagnostic
+	strict bool
+}
+
+// Strict returns a copy of d that additionally fails if the output contains
+// any error/warning diagnostic that wasn't declared in expect.
+func (d Diagnostics) Strict() Diagnostics {
+	d.strict = true
+	return d
+}
+
+func (d Diagnostics) Check(stdout, stderr string, err error) error {
+	got := parseDiagnostics(stdout, stderr)
+
+	matched := make([]bool, len(got))
+	var missing []Diagnostic
+
+	for _, want := range d.expect {
+		found := false
+		for i, g := range got {
+			if matched[i] {
+				continue
+			}
+			if g.Severity != want.Severity {
+				continue
+			}
+			if want.Builder != "" && g.Builder != want.Builder {
+				continue
+			}
+			if want.File != "" && g.File != want.File {
+				continue
+			}
+			if want.Line != 0 && g.Line != want.Line {
+				continue
+			}
+			if want.MessageRegexp != "" && !regexp.MustCompile(want.MessageRegexp).MatchString(g.Message) {
+				continue
+			}
+			matched[i] = true
+			found = true
+			break
+		}
+		if !found {
+			missing = append(missing, want)
+		}
+	}
+
+	var errs []string
+	for _, m := range missing {
+		errs = append(errs, fmt.Sprintf("missing expected %s diagnostic %s:%d: %q", m.Severity, m.File, m.Line, m.MessageRegexp))
+	}
+
+	if d.strict {
+		for i, g := range got {
+			if matched[i] {
+				continue
+			}
+			errs = append(errs, fmt.Sprintf("unexpected %s diagnostic %s:%d: %q", g.Severity, g.File, g.Line, g.Message))
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("diagnostics mismatch:\n%s", strings.Join(errs, "\n"))
+	}
+
+	return nil
+}
+
+func (d Diagnostics) Name() string {
+	if d.strict {
+		return fmt.Sprintf("Diagnostics(%d expected, strict)", len(d.expect))
+	}
+	return fmt.Sprintf("Diagnostics(%d expected)", len(d.expect))
+}
@@ -0,0 +1,65 @@
+package test
+
+import (
+	"fmt"
+	"time"
+)
+
+// Eventually wraps inner so that, instead of failing immediately, it retries
+// against freshly re-run output until inner succeeds or timeout elapses.
+//
+// This is needed for checks against asynchronous side effects - e.g. a
+// `packer build` whose registry upload completes slightly after the process
+// exits, or HCP Packer metadata that becomes visible on a delay - where the
+// condition inner checks for isn't guaranteed to hold the instant the
+// original command returns.
+//
+// rerun is called to re-probe the condition; it is typically a closure that
+// re-invokes the original command, or queries whatever side effect is being
+// waited on.
+func Eventually(inner Checker, timeout, interval time.Duration, rerun func() (stdout, stderr string, err error)) Checker {
+	return eventuallyChecker{
+		inner:    inner,
+		timeout:  timeout,
+		interval: interval,
+		rerun:    rerun,
+	}
+}
+
+type eventuallyChecker struct {
+	inner    Checker
+	timeout  time.Duration
+	interval time.Duration
+	rerun    func() (stdout, stderr string, err error)
+}
+
+func (e eventuallyChecker) Check(stdout, stderr string, err error) error {
+	attempts := 1
+	lastErr := e.inner.Check(stdout, stderr, err)
+	if lastErr == nil {
+		return nil
+	}
+
+	deadline := time.NewTimer(e.timeout)
+	defer deadline.Stop()
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-deadline.C:
+			return fmt.Errorf("%s: did not pass after %d attempt(s) within %s: %s", InferName(e.inner), attempts, e.timeout, lastErr)
+		case <-ticker.C:
+			attempts++
+			newStdout, newStderr, newErr := e.rerun()
+			lastErr = e.inner.Check(newStdout, newStderr, newErr)
+			if lastErr == nil {
+				return nil
+			}
+		}
+	}
+}
+
+func (e eventuallyChecker) Name() string {
+	return fmt.Sprintf("Eventually(%s, timeout=%s, interval=%s)", InferName(e.inner), e.timeout, e.interval)
+}
@@ -33,10 +33,6 @@ func (s Stream) String() string {
 	panic(fmt.Sprintf("Unknown stream value: %d", s))
 }
 
-type Checker interface {
-	Check(stdout, stderr string, err error) error
-}
-
 func InferName(c Checker) string {
 	if c == nil {
 		panic("nil checker - malformed test?")
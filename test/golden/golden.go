@@ -0,0 +1,235 @@
+// Package golden implements a testdata-directory harness for Packer
+// templates, in the style of golang.org/x/tools/go/analysis/analysistest:
+// a directory of `.pkr.hcl`/`.json` templates is walked, `packer validate`
+// is run against each, and the diagnostics it emits are compared against
+// inline `# want "regexp"` (or `// want "regexp"` for JSON/HCL) annotations
+// placed on the line where the error is expected.
+package golden
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/packer/test"
+)
+
+// update, when set via `-update`, causes GoldenDir to rewrite each
+// template's `# want` annotations to match what Packer actually emitted,
+// instead of comparing against them.
+var update = flag.Bool("update", false, "update golden testdata `want` annotations instead of checking them")
+
+var templateExt = regexp.MustCompile(`\.(pkr\.hcl|hcl|json)$`)
+
+// wantRe matches a `# want "regexp"` or `// want "regexp"` annotation. A
+// single line may carry more than one (e.g. an HCL block line that reports
+// both a missing and an unsupported argument), so this is not anchored to
+// end-of-line - callers use FindAllStringSubmatch to recover every
+// occurrence in order.
+var wantRe = regexp.MustCompile(`(?:#|//)\s*want\s+"((?:[^"\\]|\\.)*)"`)
+
+// diagLineRe matches Packer's `file:line,col-col: message` diagnostic form.
+var diagLineRe = regexp.MustCompile(`^(?:.*?)([\w./-]+\.(?:pkr\.hcl|hcl|json)):(\d+),\d+(?:-\d+)?:\s*(.*)$`)
+
+type want struct {
+	line    int
+	pattern string
+}
+
+type observed struct {
+	line    int
+	message string
+}
+
+// goldenDir is a Checker that, on Check, ignores the stdout/stderr/err it is
+// handed (those belong to whatever command the caller happened to wrap it
+// around) and instead drives `packer validate` across every template under
+// dir, comparing emitted diagnostics to each template's `want` annotations.
+type goldenDir struct {
+	dir  string
+	mode string
+}
+
+// GoldenDir returns a Checker that runs `packer validate` against every
+// `.pkr.hcl`/`.json` template under path and compares its diagnostics to
+// the template's inline `want` annotations. Call .Build() on the result to
+// run `packer build` instead, for templates whose diagnostics only surface
+// during a build.
+func GoldenDir(path string) goldenDir {
+	return goldenDir{dir: path, mode: "validate"}
+}
+
+// Build returns a copy of g that runs `packer build` instead of
+// `packer validate` against each template.
+func (g goldenDir) Build() goldenDir {
+	g.mode = "build"
+	return g
+}
+
+func (g goldenDir) Name() string {
+	return fmt.Sprintf("GoldenDir(%s, mode=%s)", g.dir, g.mode)
+}
+
+var _ test.Checker = goldenDir{}
+
+func (g goldenDir) Check(_, _ string, _ error) error {
+	var templates []string
+	err := filepath.Walk(g.dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && templateExt.MatchString(path) {
+			templates = append(templates, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("walking %s: %w", g.dir, err)
+	}
+	sort.Strings(templates)
+
+	var errs []string
+	for _, tmpl := range templates {
+		if err := checkTemplate(tmpl, g.mode); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("golden mismatch in %s:\n%s", g.dir, strings.Join(errs, "\n"))
+	}
+	return nil
+}
+
+func checkTemplate(path, mode string) error {
+	wants, err := parseWants(path)
+	if err != nil {
+		return fmt.Errorf("%s: parsing want annotations: %w", path, err)
+	}
+
+	out, runErr := exec.Command("packer", mode, path).CombinedOutput()
+	got := parseObserved(path, string(out))
+	_ = runErr // validate/build is expected to fail when diagnostics are expected; the diagnostics themselves are what we compare.
+
+	if *update {
+		return updateWants(path, got)
+	}
+
+	return diffWantsVsGot(path, wants, got)
+}
+
+func parseWants(path string) ([]want, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var wants []want
+	scanner := bufio.NewScanner(f)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		for _, m := range wantRe.FindAllStringSubmatch(scanner.Text(), -1) {
+			wants = append(wants, want{line: lineNum, pattern: m[1]})
+		}
+	}
+	return wants, scanner.Err()
+}
+
+func parseObserved(path, output string) []observed {
+	base := filepath.Base(path)
+	var got []observed
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		m := diagLineRe.FindStringSubmatch(scanner.Text())
+		if m == nil {
+			continue
+		}
+		if filepath.Base(m[1]) != base {
+			continue
+		}
+		line, _ := strconv.Atoi(m[2])
+		got = append(got, observed{line: line, message: m[3]})
+	}
+	return got
+}
+
+func diffWantsVsGot(path string, wants []want, got []observed) error {
+	matched := make([]bool, len(got))
+	var missing []want
+	var diff []string
+
+	for _, w := range wants {
+		re := regexp.MustCompile(w.pattern)
+		found := false
+		for i, g := range got {
+			if matched[i] || g.line != w.line {
+				continue
+			}
+			if re.MatchString(g.message) {
+				matched[i] = true
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, w)
+		}
+	}
+
+	for _, m := range missing {
+		diff = append(diff, fmt.Sprintf("-\t%s:%d: want %q", path, m.line, m.pattern))
+	}
+	for i, g := range got {
+		if !matched[i] {
+			diff = append(diff, fmt.Sprintf("+\t%s:%d: unexpected diagnostic %q", path, g.line, g.message))
+		}
+	}
+
+	if len(diff) > 0 {
+		return fmt.Errorf("%s", strings.Join(diff, "\n"))
+	}
+	return nil
+}
+
+// updateWants rewrites path's `want` annotations in place so that they match
+// got exactly, preserving every non-annotation line.
+func updateWants(path string, got []observed) error {
+	byLine := make(map[int][]string)
+	for _, g := range got {
+		byLine[g.line] = append(byLine[g.line], regexp.QuoteMeta(g.message))
+	}
+
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	lines := strings.Split(string(contents), "\n")
+	for i := range lines {
+		lineNum := i + 1
+		lines[i] = wantRe.ReplaceAllString(lines[i], "")
+		lines[i] = strings.TrimRight(lines[i], " \t")
+		if msgs, ok := byLine[lineNum]; ok {
+			commentMark := "#"
+			if strings.HasSuffix(path, ".json") {
+				commentMark = "//"
+			}
+			for _, msg := range msgs {
+				// Written raw rather than with %q: parseWants uses the quoted
+				// text as the regexp as-is, so only the quotes are escaped.
+				lines[i] = fmt.Sprintf(`%s %s want "%s"`, lines[i], commentMark, strings.ReplaceAll(msg, `"`, `\"`))
+			}
+		}
+	}
+
+	return os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0644)
+}
@@ -0,0 +1,233 @@
+package test
+
+import (
+	"bufio"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Event is a single line of Packer's `-machine-readable` / PACKER_LOG event
+// stream, of the wire form `timestamp,target,type,data...`.
+type Event struct {
+	Timestamp time.Time
+	// Target is the builder/provisioner name the event is about, or empty
+	// for messages not tied to a particular build (e.g. top-level "ui").
+	Target string
+	// Type is the event kind, e.g. "ui", "artifact", "artifact-count".
+	Type string
+	// Data holds the event's remaining comma-separated fields, unescaped.
+	Data []string
+}
+
+// unescapeMachineReadable reverses Packer's machine-readable field escaping,
+// where literal commas and newlines within a field are replaced with
+// "%!(PACKER_COMMA)" and "\n" respectively.
+func unescapeMachineReadable(field string) string {
+	field = strings.ReplaceAll(field, "%!(PACKER_COMMA)", ",")
+	field = strings.ReplaceAll(field, `\n`, "\n")
+	return field
+}
+
+// parseMachineReadable parses a `-machine-readable` event stream into
+// Events, skipping any line that isn't well-formed (e.g. interleaved
+// human-readable log noise).
+func parseMachineReadable(stream string) []Event {
+	var events []Event
+
+	scanner := bufio.NewScanner(strings.NewReader(stream))
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), ",")
+		if len(fields) < 3 {
+			continue
+		}
+
+		unixTime, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil {
+			continue
+		}
+
+		data := make([]string, len(fields)-3)
+		for i, f := range fields[3:] {
+			data[i] = unescapeMachineReadable(f)
+		}
+
+		events = append(events, Event{
+			Timestamp: time.Unix(unixTime, 0),
+			Target:    fields[1],
+			Type:      fields[2],
+			Data:      data,
+		})
+	}
+
+	return events
+}
+
+type eventExpectation struct {
+	desc      string
+	target    string
+	typ       string
+	dataIndex int
+	pattern   *regexp.Regexp
+	predicate func(Event) bool
+}
+
+func (e eventExpectation) matches(ev Event) bool {
+	if e.predicate != nil {
+		return e.predicate(ev)
+	}
+	if e.target != "" && ev.Target != e.target {
+		return false
+	}
+	if e.typ != "" && ev.Type != e.typ {
+		return false
+	}
+	if e.pattern == nil {
+		return true
+	}
+	if e.dataIndex < 0 || e.dataIndex >= len(ev.Data) {
+		return false
+	}
+	return e.pattern.MatchString(ev.Data[e.dataIndex])
+}
+
+func (e eventExpectation) String() string {
+	if e.desc != "" {
+		return e.desc
+	}
+	return fmt.Sprintf("%s,%s,data[%d]=~%q", e.target, e.typ, e.dataIndex, e.pattern)
+}
+
+type orderConstraint struct {
+	before, after string
+}
+
+type countConstraint struct {
+	target, typ string
+	n           int
+}
+
+// MachineReadable is a Checker against Packer's `-machine-readable` event
+// stream. It gives acceptance tests a stable contract against Packer's
+// structured output, instead of relying on human-readable stdout regexes
+// that churn with UI tweaks.
+type MachineReadable struct {
+	expectations []eventExpectation
+	ordering     []orderConstraint
+	counts       []countConstraint
+}
+
+// Expect requires at least one event with the given target and type whose
+// Data[dataIndex] matches pattern.
+func (m MachineReadable) Expect(target, typ string, dataIndex int, pattern *regexp.Regexp) MachineReadable {
+	m.expectations = append(m.expectations, eventExpectation{
+		target: target, typ: typ, dataIndex: dataIndex, pattern: pattern,
+	})
+	return m
+}
+
+// ExpectFunc requires at least one event matching the predicate. desc names
+// the expectation in failure output.
+func (m MachineReadable) ExpectFunc(desc string, predicate func(Event) bool) MachineReadable {
+	m.expectations = append(m.expectations, eventExpectation{desc: desc, predicate: predicate})
+	return m
+}
+
+// Before requires that some event matching firstType occur earlier in the
+// stream than any event matching secondType, e.g. `Before("ui,say", "artifact")`.
+//
+// Each side is a Type, optionally followed by a comma and the subtype found
+// in Data[0] (the convention real Packer output uses for its "ui" messages,
+// e.g. a `ui,say` event). A bare Type with no comma matches on Type alone.
+func (m MachineReadable) Before(firstType, secondType string) MachineReadable {
+	m.ordering = append(m.ordering, orderConstraint{before: firstType, after: secondType})
+	return m
+}
+
+// matchesTypeSpec reports whether ev matches a Before spec of the form
+// "type" or "type,subtype".
+func matchesTypeSpec(ev Event, spec string) bool {
+	typ, subtype, hasSubtype := strings.Cut(spec, ",")
+	if ev.Type != typ {
+		return false
+	}
+	if !hasSubtype {
+		return true
+	}
+	return len(ev.Data) > 0 && ev.Data[0] == subtype
+}
+
+// Count requires exactly n events with the given target and type.
+func (m MachineReadable) Count(target, typ string, n int) MachineReadable {
+	m.counts = append(m.counts, countConstraint{target: target, typ: typ, n: n})
+	return m
+}
+
+func (m MachineReadable) Check(stdout, stderr string, err error) error {
+	events := parseMachineReadable(stdout)
+
+	var errs []string
+
+	for _, exp := range m.expectations {
+		found := false
+		for _, ev := range events {
+			if exp.matches(ev) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			errs = append(errs, fmt.Sprintf("missing expected event: %s", exp))
+		}
+	}
+
+	for _, oc := range m.ordering {
+		beforeIdx, afterIdx := -1, -1
+		for i, ev := range events {
+			if beforeIdx == -1 && matchesTypeSpec(ev, oc.before) {
+				beforeIdx = i
+			}
+			if afterIdx == -1 && matchesTypeSpec(ev, oc.after) {
+				afterIdx = i
+			}
+		}
+		switch {
+		case beforeIdx == -1:
+			errs = append(errs, fmt.Sprintf("ordering %q before %q: no %q event observed", oc.before, oc.after, oc.before))
+		case afterIdx == -1:
+			errs = append(errs, fmt.Sprintf("ordering %q before %q: no %q event observed", oc.before, oc.after, oc.after))
+		case beforeIdx >= afterIdx:
+			errs = append(errs, fmt.Sprintf("ordering violation: %q (event %d) did not occur before %q (event %d)", oc.before, beforeIdx, oc.after, afterIdx))
+		}
+	}
+
+	for _, cc := range m.counts {
+		got := 0
+		for _, ev := range events {
+			if ev.Target == cc.target && ev.Type == cc.typ {
+				got++
+			}
+		}
+		if got != cc.n {
+			errs = append(errs, fmt.Sprintf("expected %d event(s) of %s,%s, got %d", cc.n, cc.target, cc.typ, got))
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+
+	var observed strings.Builder
+	for _, ev := range events {
+		fmt.Fprintf(&observed, "  %s,%s,%s,%s\n", ev.Timestamp.Format(time.RFC3339), ev.Target, ev.Type, strings.Join(ev.Data, ","))
+	}
+
+	return fmt.Errorf("machine-readable stream mismatch:\n%s\nobserved events:\n%s", strings.Join(errs, "\n"), observed.String())
+}
+
+func (m MachineReadable) Name() string {
+	return fmt.Sprintf("MachineReadable(%d expectation(s), %d ordering constraint(s), %d count constraint(s))",
+		len(m.expectations), len(m.ordering), len(m.counts))
+}